@@ -2,13 +2,19 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"mtls-proxy/internal/config"
 	"mtls-proxy/internal/server"
 )
 
 func main() {
-	cfg := config.NewManager("./proxy-config.json")
+	configPath := "./proxy-config.json"
+	if p := os.Getenv("CONFIG_PATH"); p != "" {
+		configPath = p
+	}
+
+	cfg := config.NewManager(configPath)
 	srv := server.New(cfg)
 	if err := srv.Start(); err != nil {
 		log.Fatalf("FATAL: %v", err)
@@ -1,29 +1,70 @@
 package auth
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"mtls-proxy/internal/config"
+	"mtls-proxy/internal/metrics"
+	"mtls-proxy/internal/ratelimit"
 )
 
-// Middleware verifies the X-API-Key header and attaches customer info.
+// limiter enforces each customer's token-bucket rate limit and concurrency
+// cap across the lifetime of the process, independent of any one
+// Middleware closure.
+var limiter = ratelimit.NewManager()
+
+// Middleware verifies the caller's identity, attaches customer info, and
+// enforces the customer's rate limit. The X-API-Key header is the primary
+// identity source; when the request arrived over mTLS with a verified
+// client certificate, its Subject CN is accepted as an alternative, letting
+// Prometheus/agent callers authenticate with mTLS instead of (or in
+// addition to) an API key.
 func Middleware(cfg *config.Manager) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			apiKey := r.Header.Get("X-API-Key")
-			if apiKey == "" {
-				http.Error(w, "Unauthorized: Missing X-API-Key header", http.StatusUnauthorized)
+			identity := r.Header.Get("X-API-Key")
+			if identity == "" {
+				identity = clientCertCN(r)
+			}
+			if identity == "" {
+				http.Error(w, "Unauthorized: Missing X-API-Key header or client certificate", http.StatusUnauthorized)
 				return
 			}
-			customer, ok := cfg.Get(apiKey)
+			customer, ok := cfg.Get(identity)
 			if !ok {
 				http.Error(w, "Unauthorized: Invalid API Key", http.StatusUnauthorized)
 				return
 			}
-			r.Header.Set("X-Target-URL", customer.URL)
-			r.Header.Set("X-Cert-Path", customer.CertPath)
-			r.Header.Set("X-Key-Path", customer.KeyPath)
+
+			release, retryAfter, ok := limiter.Acquire(identity, ratelimit.Limits{
+				RPS:           customer.RPS,
+				Burst:         customer.Burst,
+				MaxConcurrent: customer.MaxConcurrent,
+			})
+			if !ok {
+				metrics.RateLimitRejectedTotal.WithLabelValues(identity).Inc()
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds()+1)))
+				http.Error(w, fmt.Sprintf("Too Many Requests: rate limit exceeded for %q", identity), http.StatusTooManyRequests)
+				return
+			}
+			defer release()
+
+			metrics.InFlightRequests.WithLabelValues(identity).Inc()
+			defer metrics.InFlightRequests.WithLabelValues(identity).Dec()
+
+			r.Header.Set("X-Customer-ID", identity)
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// clientCertCN returns the Subject CN of the request's verified mTLS client
+// certificate, or "" if the request didn't present one.
+func clientCertCN(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	return r.TLS.PeerCertificates[0].Subject.CommonName
+}
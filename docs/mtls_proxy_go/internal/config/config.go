@@ -4,37 +4,78 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
 )
 
 // CustomerInfo holds the configuration for a single customer.
 type CustomerInfo struct {
-	URL      string `json:"url"`
-	CertPath string `json:"certPath"`
-	KeyPath  string `json:"keyPath"`
+	URL      string `json:"url" yaml:"url"`
+	CertPath string `json:"certPath" yaml:"certPath"`
+	KeyPath  string `json:"keyPath" yaml:"keyPath"`
+
+	// CertSource tells mtls.Store how to interpret CertPath/KeyPath:
+	// "file" (the default) reads them as filesystem paths, "inline" treats
+	// them as PEM content embedded directly in the config, and a
+	// "vault://..." or other backend URI fetches the material from a
+	// pluggable secret backend.
+	CertSource string `json:"certSource" yaml:"certSource"`
+
+	// TimeoutSeconds bounds how long the proxy waits for this customer's
+	// upstream per attempt. Zero uses server.defaultUpstreamTimeout.
+	TimeoutSeconds int `json:"timeoutSeconds" yaml:"timeoutSeconds"`
+
+	// MaxRetries bounds how many times a failed upstream attempt (5xx or
+	// connection error) is retried with exponential backoff. Zero uses
+	// server.defaultMaxRetries.
+	MaxRetries int `json:"maxRetries" yaml:"maxRetries"`
+
+	// RPS and Burst configure this customer's token-bucket rate limit.
+	// Zero uses ratelimit.DefaultRPS/DefaultBurst.
+	RPS   float64 `json:"rps" yaml:"rps"`
+	Burst int     `json:"burst" yaml:"burst"`
+
+	// MaxConcurrent bounds how many of this customer's requests may be
+	// in flight at once. Zero uses Burst.
+	MaxConcurrent int `json:"maxConcurrent" yaml:"maxConcurrent"`
 }
 
-// Manager handles loading and reloading configuration files.
+// Manager handles loading and reloading configuration files, and supports
+// live updates to individual customers via the admin API.
 type Manager struct {
 	Path string
 	mu   sync.RWMutex
 	data map[string]CustomerInfo
 }
 
-// NewManager creates a new config manager with the given path.
+// NewManager creates a new config manager with the given path. The file
+// format (JSON or YAML) is auto-detected from the extension in Load.
 func NewManager(path string) *Manager {
 	return &Manager{Path: path}
 }
 
 // Load reads the configuration from disk and replaces the current config.
+// Both JSON and YAML are supported, chosen by the file's extension
+// (".yaml"/".yml" for YAML, anything else for JSON).
 func (m *Manager) Load() error {
 	contents, err := os.ReadFile(m.Path)
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
-	var cfg map[string]CustomerInfo
-	if err := json.Unmarshal(contents, &cfg); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+	cfg := make(map[string]CustomerInfo)
+	switch strings.ToLower(filepath.Ext(m.Path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(contents, &cfg); err != nil {
+			return fmt.Errorf("failed to parse config: %w", err)
+		}
+	default:
+		if err := json.Unmarshal(contents, &cfg); err != nil {
+			return fmt.Errorf("failed to parse config: %w", err)
+		}
 	}
 	m.mu.Lock()
 	m.data = cfg
@@ -56,3 +97,73 @@ func (m *Manager) Count() int {
 	defer m.mu.RUnlock()
 	return len(m.data)
 }
+
+// All returns a copy of the full routing table, keyed by API key.
+func (m *Manager) All() map[string]CustomerInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	all := make(map[string]CustomerInfo, len(m.data))
+	for k, v := range m.data {
+		all[k] = v
+	}
+	return all
+}
+
+// Set adds or replaces a single customer's entry via an atomic swap of the
+// underlying map, without touching the on-disk config file.
+func (m *Manager) Set(apiKey string, info CustomerInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	next := make(map[string]CustomerInfo, len(m.data)+1)
+	for k, v := range m.data {
+		next[k] = v
+	}
+	next[apiKey] = info
+	m.data = next
+}
+
+// Delete removes a single customer's entry via an atomic swap of the
+// underlying map. It reports whether the customer existed.
+func (m *Manager) Delete(apiKey string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.data[apiKey]; !ok {
+		return false
+	}
+	next := make(map[string]CustomerInfo, len(m.data)-1)
+	for k, v := range m.data {
+		if k != apiKey {
+			next[k] = v
+		}
+	}
+	m.data = next
+	return true
+}
+
+// Watch begins watching Path for changes and reloads the config
+// automatically when it does, calling onReload with the result of each
+// attempt. It returns the underlying fsnotify.Watcher so the caller can
+// Close it on shutdown. Callers that only want SIGHUP-triggered reloads can
+// ignore this method entirely.
+func (m *Manager) Watch(onReload func(error)) (*fsnotify.Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start config watcher: %w", err)
+	}
+	if err := w.Add(filepath.Dir(m.Path)); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("failed to watch config directory: %w", err)
+	}
+	go func() {
+		for event := range w.Events {
+			if filepath.Clean(event.Name) != filepath.Clean(m.Path) {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			onReload(m.Load())
+		}
+	}()
+	return w, nil
+}
@@ -0,0 +1,67 @@
+// Package metrics holds the Prometheus collectors the proxy exposes about
+// its own health on the admin listener's /metrics endpoint.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// RequestsTotal counts proxied requests by customer, job, and the
+	// upstream's response status.
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "proxy_requests_total",
+		Help: "Total number of proxied requests, by customer, job, and upstream status.",
+	}, []string{"customer_id", "job", "status"})
+
+	// UpstreamLatencySeconds observes how long the upstream pushgateway took
+	// to respond.
+	UpstreamLatencySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "proxy_upstream_latency_seconds",
+		Help: "Latency of upstream pushgateway requests.",
+	}, []string{"customer_id", "job"})
+
+	// CertLoadFailuresTotal counts failures to load a customer's mTLS client
+	// certificate.
+	CertLoadFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "proxy_cert_load_failures_total",
+		Help: "Number of mTLS client certificate load failures, by customer.",
+	}, []string{"customer_id"})
+
+	// ConfigReloadsTotal counts successful configuration reloads.
+	ConfigReloadsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "proxy_config_reloads_total",
+		Help: "Number of successful configuration reloads.",
+	})
+
+	// ActiveCertClients reports how many mTLS HTTP clients are currently
+	// cached in memory.
+	ActiveCertClients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "proxy_active_cert_clients",
+		Help: "Number of mTLS HTTP clients currently cached.",
+	})
+
+	// RateLimitRejectedTotal counts requests rejected with 429 because a
+	// customer's token bucket or concurrency cap was exhausted.
+	RateLimitRejectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "proxy_rate_limit_rejected_total",
+		Help: "Number of requests rejected by per-customer rate limiting, by customer.",
+	}, []string{"customer_id"})
+
+	// InFlightRequests reports how many requests are currently in flight,
+	// by customer.
+	InFlightRequests = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "proxy_in_flight_requests",
+		Help: "Number of requests currently in flight, by customer.",
+	}, []string{"customer_id"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		RequestsTotal,
+		UpstreamLatencySeconds,
+		CertLoadFailuresTotal,
+		ConfigReloadsTotal,
+		ActiveCertClients,
+		RateLimitRejectedTotal,
+		InFlightRequests,
+	)
+}
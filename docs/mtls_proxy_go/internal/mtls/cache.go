@@ -0,0 +1,183 @@
+package mtls
+
+import (
+	"container/list"
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"mtls-proxy/internal/metrics"
+)
+
+// defaultTTL bounds how long a cached client is trusted before Get reloads
+// its certificate, even if no invalidation signal arrived.
+const defaultTTL = 10 * time.Minute
+
+// CustomerCert identifies the certificate material behind a cache entry.
+type CustomerCert struct {
+	CustomerID string
+	CertSource string
+	CertPath   string
+	KeyPath    string
+}
+
+type cacheEntry struct {
+	client    *http.Client
+	cert      tls.Certificate
+	expiresAt time.Time
+	elem      *list.Element
+}
+
+// Cache is an LRU of *http.Client keyed by customer ID, backed by a Store.
+// Entries are invalidated when their TTL expires, when Invalidate/
+// InvalidateAll is called (e.g. on SIGHUP), or when the underlying cert
+// file changes on disk (via fsnotify, for file-backed customers).
+type Cache struct {
+	vault SecretBackend
+	cap   int
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	order   *list.List // front = most recently used; elem.Value = customer ID
+
+	watcher *fsnotify.Watcher
+	watched map[string]string // watched file path -> customer ID
+}
+
+// NewCache creates a client cache with room for at most capacity customers.
+// vault may be nil if no secret backend is configured.
+func NewCache(capacity int, vault SecretBackend) *Cache {
+	c := &Cache{
+		vault:   vault,
+		cap:     capacity,
+		ttl:     defaultTTL,
+		entries: make(map[string]*cacheEntry),
+		order:   list.New(),
+		watched: make(map[string]string),
+	}
+	if w, err := fsnotify.NewWatcher(); err == nil {
+		c.watcher = w
+		go c.watchLoop()
+	}
+	return c
+}
+
+// Get returns a cached *http.Client for the customer, loading and caching
+// one via Store if absent, expired, or invalidated.
+func (c *Cache) Get(cust CustomerCert) (*http.Client, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[cust.CustomerID]; ok && time.Now().Before(e.expiresAt) {
+		c.order.MoveToFront(e.elem)
+		client := e.client
+		c.mu.Unlock()
+		return client, nil
+	}
+	c.mu.Unlock()
+
+	store := StoreFor(cust.CertSource, c.vault)
+	cert, err := store.Load(cust.CertPath, cust.KeyPath)
+	if err != nil {
+		metrics.CertLoadFailuresTotal.WithLabelValues(cust.CustomerID).Inc()
+		return nil, err
+	}
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{Certificates: []tls.Certificate{cert}}},
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[cust.CustomerID]
+	if !ok {
+		e = &cacheEntry{}
+		e.elem = c.order.PushFront(cust.CustomerID)
+		c.entries[cust.CustomerID] = e
+		c.evictIfFull()
+	} else {
+		c.order.MoveToFront(e.elem)
+	}
+	e.client = client
+	e.cert = cert
+	e.expiresAt = time.Now().Add(c.ttl)
+	if cust.CertSource == "" || cust.CertSource == "file" {
+		c.watchFile(cust.CertPath, cust.CustomerID)
+	}
+	metrics.ActiveCertClients.Set(float64(c.order.Len()))
+	return client, nil
+}
+
+// evictIfFull drops the least-recently-used entry once the cache is over
+// capacity. Must be called with c.mu held.
+func (c *Cache) evictIfFull() {
+	if c.cap <= 0 || c.order.Len() <= c.cap {
+		return
+	}
+	oldest := c.order.Back()
+	if oldest == nil {
+		return
+	}
+	id := oldest.Value.(string)
+	c.order.Remove(oldest)
+	delete(c.entries, id)
+}
+
+// Invalidate drops the cached client for one customer, forcing a reload on
+// the next Get.
+func (c *Cache) Invalidate(customerID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[customerID]; ok {
+		c.order.Remove(e.elem)
+		delete(c.entries, customerID)
+	}
+	metrics.ActiveCertClients.Set(float64(c.order.Len()))
+}
+
+// InvalidateAll drops every cached client, e.g. in response to SIGHUP.
+func (c *Cache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+	c.order.Init()
+	metrics.ActiveCertClients.Set(0)
+}
+
+// watchFile registers certPath with fsnotify so changes invalidate
+// customerID's cache entry. Must be called with c.mu held.
+func (c *Cache) watchFile(certPath, customerID string) {
+	if c.watcher == nil || certPath == "" {
+		return
+	}
+	if _, already := c.watched[certPath]; already {
+		return
+	}
+	if err := c.watcher.Add(certPath); err == nil {
+		c.watched[certPath] = customerID
+	}
+}
+
+func (c *Cache) watchLoop() {
+	for event := range c.watcher.Events {
+		if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+			continue
+		}
+		c.mu.Lock()
+		if customerID, ok := c.watched[event.Name]; ok {
+			if e, ok := c.entries[customerID]; ok {
+				c.order.Remove(e.elem)
+				delete(c.entries, customerID)
+			}
+			// A removed or renamed file is no longer watched; forget it so
+			// the next Get re-registers whatever file now sits at the path.
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				delete(c.watched, event.Name)
+			}
+			metrics.ActiveCertClients.Set(float64(c.order.Len()))
+		}
+		c.mu.Unlock()
+	}
+}
+
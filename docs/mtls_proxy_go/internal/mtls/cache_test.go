@@ -0,0 +1,152 @@
+package mtls
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// generateTestCertPEM returns a self-signed certificate/key pair, PEM
+// encoded, suitable for CustomerCert.CertSource == "inline".
+func generateTestCertPEM(t *testing.T) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	certBuf := &bytes.Buffer{}
+	pem.Encode(certBuf, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	keyBuf := &bytes.Buffer{}
+	pem.Encode(keyBuf, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certBuf.Bytes(), keyBuf.Bytes()
+}
+
+func TestCacheGetCachesClient(t *testing.T) {
+	certPEM, keyPEM := generateTestCertPEM(t)
+	c := NewCache(10, nil)
+	cust := CustomerCert{CustomerID: "cust-a", CertSource: "inline", CertPath: string(certPEM), KeyPath: string(keyPEM)}
+
+	client1, err := c.Get(cust)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client2, err := c.Get(cust)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client1 != client2 {
+		t.Fatal("expected the second Get to return the cached client, not a freshly loaded one")
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	certPEM, keyPEM := generateTestCertPEM(t)
+	c := NewCache(2, nil)
+
+	custA := CustomerCert{CustomerID: "cust-a", CertSource: "inline", CertPath: string(certPEM), KeyPath: string(keyPEM)}
+	custB := CustomerCert{CustomerID: "cust-b", CertSource: "inline", CertPath: string(certPEM), KeyPath: string(keyPEM)}
+	custC := CustomerCert{CustomerID: "cust-c", CertSource: "inline", CertPath: string(certPEM), KeyPath: string(keyPEM)}
+
+	if _, err := c.Get(custA); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Get(custB); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Touch cust-a again so cust-b becomes the least-recently-used entry.
+	if _, err := c.Get(custA); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Get(custC); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := c.entries["cust-b"]; ok {
+		t.Fatal("expected cust-b to be evicted as the least-recently-used entry")
+	}
+	if _, ok := c.entries["cust-a"]; !ok {
+		t.Fatal("expected cust-a to survive eviction since it was touched more recently")
+	}
+	if _, ok := c.entries["cust-c"]; !ok {
+		t.Fatal("expected cust-c, the most recent entry, to be cached")
+	}
+}
+
+func TestCacheGetReloadsAfterTTLExpiry(t *testing.T) {
+	certPEM, keyPEM := generateTestCertPEM(t)
+	c := NewCache(10, nil)
+	c.ttl = time.Millisecond
+
+	cust := CustomerCert{CustomerID: "cust-a", CertSource: "inline", CertPath: string(certPEM), KeyPath: string(keyPEM)}
+	client1, err := c.Get(cust)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	client2, err := c.Get(cust)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client1 == client2 {
+		t.Fatal("expected Get to reload the client once its TTL had expired")
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	certPEM, keyPEM := generateTestCertPEM(t)
+	c := NewCache(10, nil)
+	cust := CustomerCert{CustomerID: "cust-a", CertSource: "inline", CertPath: string(certPEM), KeyPath: string(keyPEM)}
+
+	if _, err := c.Get(cust); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Invalidate("cust-a")
+	if _, ok := c.entries["cust-a"]; ok {
+		t.Fatal("expected Invalidate to drop the cached entry")
+	}
+}
+
+func TestCacheInvalidateAll(t *testing.T) {
+	certPEM, keyPEM := generateTestCertPEM(t)
+	c := NewCache(10, nil)
+	custA := CustomerCert{CustomerID: "cust-a", CertSource: "inline", CertPath: string(certPEM), KeyPath: string(keyPEM)}
+	custB := CustomerCert{CustomerID: "cust-b", CertSource: "inline", CertPath: string(certPEM), KeyPath: string(keyPEM)}
+
+	if _, err := c.Get(custA); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Get(custB); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.InvalidateAll()
+
+	if len(c.entries) != 0 {
+		t.Fatalf("expected InvalidateAll to clear every entry, got %d remaining", len(c.entries))
+	}
+}
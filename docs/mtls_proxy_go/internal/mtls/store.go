@@ -0,0 +1,81 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// Store resolves a customer's certificate/key material into a tls.Certificate,
+// independent of where that material actually lives.
+type Store interface {
+	Load(certPath, keyPath string) (tls.Certificate, error)
+}
+
+// FileStore loads certificates from the local filesystem. It is the default
+// Store for CustomerInfo.CertSource == "file" (or unset).
+type FileStore struct{}
+
+// Load reads certPath/keyPath as filesystem paths.
+func (FileStore) Load(certPath, keyPath string) (tls.Certificate, error) {
+	return tls.LoadX509KeyPair(certPath, keyPath)
+}
+
+// InlineStore treats certPath/keyPath as PEM-encoded certificate and key
+// material embedded directly in the config, for CustomerInfo.CertSource ==
+// "inline". Useful for tests and small deployments that don't want cert
+// files on disk.
+type InlineStore struct{}
+
+// Load parses certPath/keyPath as PEM content.
+func (InlineStore) Load(certPath, keyPath string) (tls.Certificate, error) {
+	return tls.X509KeyPair([]byte(certPath), []byte(keyPath))
+}
+
+// SecretBackend fetches PEM-encoded certificate/key material from an
+// external secret store (e.g. HashiCorp Vault or AWS Secrets Manager) given
+// a backend-specific reference.
+type SecretBackend interface {
+	GetCertKeyPair(ref string) (certPEM, keyPEM []byte, err error)
+}
+
+// SecretStore loads certificates via a pluggable SecretBackend, for
+// CustomerInfo.CertSource values like "vault://secret/data/acme". The
+// scheme prefix is stripped before the reference is passed to Backend.
+type SecretStore struct {
+	Backend SecretBackend
+}
+
+// Load fetches the cert/key pair from the backend using certPath as the
+// reference; keyPath is unused since secret backends return both halves
+// from a single reference. Load returns an error, rather than panicking,
+// if no Backend is configured.
+func (s SecretStore) Load(certPath, _ string) (tls.Certificate, error) {
+	if s.Backend == nil {
+		return tls.Certificate{}, fmt.Errorf("no secret backend configured for cert source %q", certPath)
+	}
+	ref := certPath
+	if idx := strings.Index(ref, "://"); idx != -1 {
+		ref = ref[idx+len("://"):]
+	}
+	certPEM, keyPEM, err := s.Backend.GetCertKeyPair(ref)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to fetch secret %q: %w", ref, err)
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// StoreFor picks the Store implementation for a CustomerInfo.CertSource
+// discriminator. vault may be nil if no secret backend is configured; a
+// "vault://" CertSource then fails with an error at Load time (SecretStore.Load)
+// rather than panicking.
+func StoreFor(certSource string, vault SecretBackend) Store {
+	switch {
+	case certSource == "inline":
+		return InlineStore{}
+	case strings.Contains(certSource, "://"):
+		return SecretStore{Backend: vault}
+	default:
+		return FileStore{}
+	}
+}
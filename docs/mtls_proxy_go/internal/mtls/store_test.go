@@ -0,0 +1,34 @@
+package mtls
+
+import "testing"
+
+func TestSecretStoreLoadWithNilBackendReturnsError(t *testing.T) {
+	s := SecretStore{}
+	if _, err := s.Load("vault://secret/data/acme", ""); err == nil {
+		t.Fatal("expected an error, not a panic, when no secret backend is configured")
+	}
+}
+
+type stubSecretBackend struct {
+	certPEM, keyPEM []byte
+	err             error
+}
+
+func (b stubSecretBackend) GetCertKeyPair(ref string) ([]byte, []byte, error) {
+	return b.certPEM, b.keyPEM, b.err
+}
+
+func TestStoreForVaultWithNilBackendDoesNotPanic(t *testing.T) {
+	store := StoreFor("vault://secret/data/acme", nil)
+	if _, err := store.Load("vault://secret/data/acme", ""); err == nil {
+		t.Fatal("expected an error when StoreFor resolves a vault:// source with no backend configured")
+	}
+}
+
+func TestStoreForVaultUsesBackend(t *testing.T) {
+	certPEM, keyPEM := generateTestCertPEM(t)
+	store := StoreFor("vault://secret/data/acme", stubSecretBackend{certPEM: certPEM, keyPEM: keyPEM})
+	if _, err := store.Load("vault://secret/data/acme", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
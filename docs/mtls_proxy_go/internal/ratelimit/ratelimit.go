@@ -0,0 +1,105 @@
+// Package ratelimit enforces a per-customer token-bucket rate limit and
+// concurrency cap, so one noisy customer can't exhaust the proxy for
+// everyone else.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultRPS and DefaultBurst apply to customers that don't set
+// CustomerInfo.RPS/Burst.
+const (
+	DefaultRPS   = 50
+	DefaultBurst = 100
+)
+
+// Limits describes one customer's rate and concurrency limits. Zero values
+// fall back to the defaults above (and Burst for MaxConcurrent).
+type Limits struct {
+	RPS           float64
+	Burst         int
+	MaxConcurrent int
+}
+
+type customerLimiter struct {
+	limits Limits
+	tokens *rate.Limiter
+	sem    chan struct{}
+}
+
+// Manager enforces Limits per customer ID. The zero value is not usable;
+// construct with NewManager.
+type Manager struct {
+	mu       sync.Mutex
+	limiters map[string]*customerLimiter
+}
+
+// NewManager creates an empty rate limit Manager.
+func NewManager() *Manager {
+	return &Manager{limiters: make(map[string]*customerLimiter)}
+}
+
+// limiterFor returns the cached limiter for customerID, recreating it if
+// limits has changed since it was created (e.g. an admin API update to
+// RPS/Burst/MaxConcurrent) so live config changes take effect on a
+// customer's very next request rather than being silently ignored until
+// the process restarts.
+func (m *Manager) limiterFor(customerID string, limits Limits) *customerLimiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cl, ok := m.limiters[customerID]; ok && cl.limits == limits {
+		return cl
+	}
+
+	rps := limits.RPS
+	if rps <= 0 {
+		rps = DefaultRPS
+	}
+	burst := limits.Burst
+	if burst <= 0 {
+		burst = DefaultBurst
+	}
+	maxConcurrent := limits.MaxConcurrent
+	if maxConcurrent <= 0 {
+		maxConcurrent = burst
+	}
+
+	cl := &customerLimiter{
+		limits: limits,
+		tokens: rate.NewLimiter(rate.Limit(rps), burst),
+		sem:    make(chan struct{}, maxConcurrent),
+	}
+	m.limiters[customerID] = cl
+	return cl
+}
+
+// Acquire reserves a rate-limit token and a concurrency slot for
+// customerID. If the bucket or the concurrency cap is exhausted, ok is
+// false and retryAfter is how long the caller should tell the client to
+// wait. Otherwise the caller must call release exactly once when the
+// request completes.
+func (m *Manager) Acquire(customerID string, limits Limits) (release func(), retryAfter time.Duration, ok bool) {
+	cl := m.limiterFor(customerID, limits)
+
+	reservation := cl.tokens.Reserve()
+	if !reservation.OK() {
+		return nil, time.Second, false
+	}
+	if delay := reservation.Delay(); delay > 0 {
+		reservation.Cancel()
+		return nil, delay, false
+	}
+
+	select {
+	case cl.sem <- struct{}{}:
+	default:
+		reservation.Cancel()
+		return nil, time.Second, false
+	}
+
+	return func() { <-cl.sem }, 0, true
+}
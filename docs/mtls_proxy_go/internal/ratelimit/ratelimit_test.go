@@ -0,0 +1,71 @@
+package ratelimit
+
+import "testing"
+
+func TestAcquireEnforcesBurst(t *testing.T) {
+	m := NewManager()
+	limits := Limits{RPS: 1, Burst: 2, MaxConcurrent: 2}
+
+	for i := 0; i < 2; i++ {
+		_, _, ok := m.Acquire("cust-a", limits)
+		if !ok {
+			t.Fatalf("request %d: expected burst capacity to allow the request", i)
+		}
+	}
+
+	if _, _, ok := m.Acquire("cust-a", limits); ok {
+		t.Fatal("expected burst to be exhausted on the third request")
+	}
+}
+
+func TestAcquireEnforcesMaxConcurrent(t *testing.T) {
+	m := NewManager()
+	limits := Limits{RPS: 1000, Burst: 1000, MaxConcurrent: 1}
+
+	release, _, ok := m.Acquire("cust-b", limits)
+	if !ok {
+		t.Fatal("expected the first request to acquire the only concurrency slot")
+	}
+
+	if _, _, ok := m.Acquire("cust-b", limits); ok {
+		t.Fatal("expected a second concurrent request to be rejected while the slot is held")
+	}
+
+	release()
+
+	if _, _, ok := m.Acquire("cust-b", limits); !ok {
+		t.Fatal("expected a request to succeed once the slot was released")
+	}
+}
+
+func TestAcquireIsolatesCustomers(t *testing.T) {
+	m := NewManager()
+	limits := Limits{RPS: 1, Burst: 1, MaxConcurrent: 1}
+
+	if _, _, ok := m.Acquire("cust-a", limits); !ok {
+		t.Fatal("expected cust-a's first request to succeed")
+	}
+	if _, _, ok := m.Acquire("cust-b", limits); !ok {
+		t.Fatal("expected cust-b's limiter to be independent of cust-a's")
+	}
+}
+
+func TestLimiterForPicksUpChangedLimits(t *testing.T) {
+	m := NewManager()
+
+	// Exhaust a one-request burst under the original limits.
+	original := Limits{RPS: 1, Burst: 1, MaxConcurrent: 1}
+	if _, _, ok := m.Acquire("cust-a", original); !ok {
+		t.Fatal("expected the first request under the original limits to succeed")
+	}
+	if _, _, ok := m.Acquire("cust-a", original); ok {
+		t.Fatal("expected the burst to be exhausted under the original limits")
+	}
+
+	// An admin update to a larger burst should take effect immediately,
+	// rather than being stuck behind the limiter cached on first use.
+	updated := Limits{RPS: 1, Burst: 5, MaxConcurrent: 5}
+	if _, _, ok := m.Acquire("cust-a", updated); !ok {
+		t.Fatal("expected updated limits to be picked up instead of reusing the stale cached limiter")
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"mtls-proxy/internal/config"
+	"mtls-proxy/internal/metrics"
+)
+
+// adminBearerMiddleware requires an "Authorization: Bearer <token>" header
+// matching the configured admin token. An empty token rejects every
+// request, so the management API is disabled unless ADMIN_TOKEN is set.
+func adminBearerMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || !ok || got != token {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// customerView is the admin-facing projection of config.CustomerInfo. It
+// deliberately omits CertPath/KeyPath: for CertSource == "inline" those
+// hold raw certificate/key PEM, and even for "file" sources a path can leak
+// more than operators scraping the routing table need to see.
+type customerView struct {
+	URL            string  `json:"url"`
+	CertSource     string  `json:"certSource"`
+	TimeoutSeconds int     `json:"timeoutSeconds"`
+	MaxRetries     int     `json:"maxRetries"`
+	RPS            float64 `json:"rps"`
+	Burst          int     `json:"burst"`
+	MaxConcurrent  int     `json:"maxConcurrent"`
+}
+
+func redactCustomer(info config.CustomerInfo) customerView {
+	return customerView{
+		URL:            info.URL,
+		CertSource:     info.CertSource,
+		TimeoutSeconds: info.TimeoutSeconds,
+		MaxRetries:     info.MaxRetries,
+		RPS:            info.RPS,
+		Burst:          info.Burst,
+		MaxConcurrent:  info.MaxConcurrent,
+	}
+}
+
+// managementRouter builds the admin routing-table API: reading and editing
+// customers and triggering a config reload. Customer edits are applied by an
+// atomic swap of Manager's underlying map without touching the config file;
+// POST /reload re-reads the file through the same Load that SIGHUP and the
+// file watcher use.
+func (p *ProxyServer) managementRouter(token string) http.Handler {
+	r := chi.NewRouter()
+	r.Use(adminBearerMiddleware(token))
+
+	r.Get("/customers", func(w http.ResponseWriter, r *http.Request) {
+		all := p.Config.All()
+		redacted := make(map[string]customerView, len(all))
+		for id, info := range all {
+			redacted[id] = redactCustomer(info)
+		}
+		json.NewEncoder(w).Encode(redacted)
+	})
+
+	r.Put("/customers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var info config.CustomerInfo
+		if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id := chi.URLParam(r, "id")
+		p.Config.Set(id, info)
+		p.certCache.Invalidate(id)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.Delete("/customers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		if !p.Config.Delete(id) {
+			http.Error(w, "customer not found", http.StatusNotFound)
+			return
+		}
+		p.certCache.Invalidate(id)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.Post("/reload", func(w http.ResponseWriter, r *http.Request) {
+		if err := p.Config.Load(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		p.certCache.InvalidateAll()
+		metrics.ConfigReloadsTotal.Inc()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return r
+}
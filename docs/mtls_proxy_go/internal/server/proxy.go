@@ -0,0 +1,156 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"mtls-proxy/internal/config"
+	"mtls-proxy/internal/metrics"
+)
+
+const (
+	// defaultUpstreamTimeout bounds a single upstream attempt when a
+	// customer doesn't set CustomerInfo.TimeoutSeconds.
+	defaultUpstreamTimeout = 30 * time.Second
+
+	// defaultMaxRetries bounds retries when a customer doesn't set
+	// CustomerInfo.MaxRetries.
+	defaultMaxRetries = 2
+
+	// maxRequestBodyBytes caps the size of a request body the proxy will
+	// buffer and forward upstream.
+	maxRequestBodyBytes = 10 << 20 // 10MiB
+
+	retryBaseBackoff = 100 * time.Millisecond
+)
+
+// newReverseProxy builds a *httputil.ReverseProxy for a single request,
+// forwarding to the customer's upstream over the given (already mTLS
+// configured) client and retrying transient failures.
+func newReverseProxy(customer config.CustomerInfo, client *http.Client, customerID, job, instance string, reqLogger *slog.Logger) (*httputil.ReverseProxy, error) {
+	target, err := url.Parse(customer.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream URL %q: %w", customer.URL, err)
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.Host = target.Host
+			req.URL.Path = fmt.Sprintf("/metrics/job/%s/instance/%s", job, instance)
+			// req.URL.RawQuery and the cloned request headers (including
+			// Content-Encoding and User-Agent) are left untouched.
+		},
+		Transport: &retryingRoundTripper{
+			base:       client.Transport,
+			timeout:    timeoutFor(customer),
+			maxRetries: maxRetriesFor(customer),
+			job:        job,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			status := http.StatusBadGateway
+			if errors.Is(err, context.DeadlineExceeded) {
+				status = http.StatusGatewayTimeout
+			}
+			reqLogger.Error("proxy request failed", "error", err, "status", status)
+			metrics.RequestsTotal.WithLabelValues(customerID, job, "error").Inc()
+			http.Error(w, http.StatusText(status), status)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			metrics.RequestsTotal.WithLabelValues(customerID, job, fmt.Sprintf("%d", resp.StatusCode)).Inc()
+			return nil
+		},
+	}
+	return proxy, nil
+}
+
+func timeoutFor(customer config.CustomerInfo) time.Duration {
+	if customer.TimeoutSeconds <= 0 {
+		return defaultUpstreamTimeout
+	}
+	return time.Duration(customer.TimeoutSeconds) * time.Second
+}
+
+func maxRetriesFor(customer config.CustomerInfo) int {
+	if customer.MaxRetries <= 0 {
+		return defaultMaxRetries
+	}
+	return customer.MaxRetries
+}
+
+// retryingRoundTripper wraps a base RoundTripper with a per-attempt timeout
+// and bounded retries with exponential backoff on 5xx responses and
+// connection errors.
+type retryingRoundTripper struct {
+	base       http.RoundTripper
+	timeout    time.Duration
+	maxRetries int
+	job        string
+}
+
+func (rt *retryingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to buffer request body: %w", err)
+		}
+	}
+
+	customerID := req.Header.Get("X-Customer-ID")
+	backoff := retryBaseBackoff
+	var lastErr error
+	for i := 0; i <= rt.maxRetries; i++ {
+		ctx, cancel := context.WithTimeout(req.Context(), rt.timeout)
+		attemptReq := req.Clone(ctx)
+		if body != nil {
+			attemptReq.Body = io.NopCloser(bytes.NewReader(body))
+			attemptReq.ContentLength = int64(len(body))
+		}
+
+		attemptStart := time.Now()
+		resp, err := rt.base.RoundTrip(attemptReq)
+		switch {
+		case err != nil:
+			cancel()
+			lastErr = err
+		case resp.StatusCode >= http.StatusInternalServerError:
+			resp.Body.Close()
+			cancel()
+			lastErr = fmt.Errorf("upstream returned %s", resp.Status)
+		default:
+			metrics.UpstreamLatencySeconds.WithLabelValues(customerID, rt.job).Observe(time.Since(attemptStart).Seconds())
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		if i == rt.maxRetries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return nil, lastErr
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
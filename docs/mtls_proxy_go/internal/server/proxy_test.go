@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// stubRoundTripper records each attempt's body and returns the next response
+// or error off its queue, so tests can script exactly how many times an
+// upstream fails before succeeding.
+type stubRoundTripper struct {
+	responses []*http.Response
+	errs      []error
+	bodies    []string
+	calls     int
+}
+
+func (s *stubRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		s.bodies = append(s.bodies, string(b))
+	} else {
+		s.bodies = append(s.bodies, "")
+	}
+	i := s.calls
+	s.calls++
+	var err error
+	if i < len(s.errs) {
+		err = s.errs[i]
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.responses[i], nil
+}
+
+func newResponse(status int) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+		Header:     make(http.Header),
+	}
+}
+
+func newTestRequest(t *testing.T, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "http://upstream.example/metrics/job/x/instance/y", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	return req
+}
+
+func TestRetryingRoundTripperRetriesOn5xx(t *testing.T) {
+	base := &stubRoundTripper{
+		responses: []*http.Response{newResponse(http.StatusInternalServerError), newResponse(http.StatusOK)},
+	}
+	rt := &retryingRoundTripper{base: base, timeout: time.Second, maxRetries: 2, job: "push"}
+
+	resp, err := rt.RoundTrip(newTestRequest(t, "payload"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected final response to be 200, got %d", resp.StatusCode)
+	}
+	if base.calls != 2 {
+		t.Fatalf("expected exactly 2 attempts, got %d", base.calls)
+	}
+}
+
+func TestRetryingRoundTripperReplaysBodyAcrossRetries(t *testing.T) {
+	base := &stubRoundTripper{
+		responses: []*http.Response{newResponse(http.StatusInternalServerError), newResponse(http.StatusOK)},
+	}
+	rt := &retryingRoundTripper{base: base, timeout: time.Second, maxRetries: 2, job: "push"}
+
+	if _, err := rt.RoundTrip(newTestRequest(t, "payload")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(base.bodies) != 2 || base.bodies[0] != "payload" || base.bodies[1] != "payload" {
+		t.Fatalf("expected the request body to be replayed unchanged on every attempt, got %v", base.bodies)
+	}
+}
+
+func TestRetryingRoundTripperGivesUpAfterMaxRetries(t *testing.T) {
+	base := &stubRoundTripper{
+		responses: []*http.Response{
+			newResponse(http.StatusInternalServerError),
+			newResponse(http.StatusInternalServerError),
+			newResponse(http.StatusInternalServerError),
+		},
+	}
+	rt := &retryingRoundTripper{base: base, timeout: time.Second, maxRetries: 2, job: "push"}
+
+	_, err := rt.RoundTrip(newTestRequest(t, "payload"))
+	if err == nil {
+		t.Fatal("expected an error once all retries are exhausted")
+	}
+	if base.calls != 3 {
+		t.Fatalf("expected maxRetries+1 = 3 attempts, got %d", base.calls)
+	}
+}
+
+func TestRetryingRoundTripperDoesNotRetryOnSuccess(t *testing.T) {
+	base := &stubRoundTripper{
+		responses: []*http.Response{newResponse(http.StatusOK)},
+	}
+	rt := &retryingRoundTripper{base: base, timeout: time.Second, maxRetries: 2, job: "push"}
+
+	if _, err := rt.RoundTrip(newTestRequest(t, "payload")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if base.calls != 1 {
+		t.Fatalf("expected a single attempt on immediate success, got %d", base.calls)
+	}
+}
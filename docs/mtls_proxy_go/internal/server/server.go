@@ -1,97 +1,316 @@
 package server
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"mtls-proxy/internal/auth"
 	"mtls-proxy/internal/config"
+	"mtls-proxy/internal/metrics"
 	"mtls-proxy/internal/mtls"
 )
 
+// customerCertCacheSize bounds how many customers' mTLS clients are kept
+// warm at once.
+const customerCertCacheSize = 256
+
+// logger is the proxy's structured logger. Per-request lines, logged through
+// proxyHandler's reqLogger, carry "customer_id", "job", "instance", and
+// "request_id" (JSON keys chosen to line up with the Prometheus labels they
+// describe); startup, reload and shutdown lines carry none of these.
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
 // ProxyServer encapsulates the HTTP server and configuration management.
 type ProxyServer struct {
-	Config   *config.Manager
-	router   *chi.Mux
-	HTTPPort string
+	Config         *config.Manager
+	router         *chi.Mux
+	certCache      *mtls.Cache
+	HTTPPort       string
+	AdminPort      string
+	ManagementPort string
+	AdminToken     string
+
+	// TLS serving options, populated from the environment in Start.
+	TLSPort      string
+	TLSCertPath  string
+	TLSKeyPath   string
+	ClientCAPath string
 }
 
 // New creates a ProxyServer with routes configured.
 func New(cfg *config.Manager) *ProxyServer {
 	r := chi.NewRouter()
-	ps := &ProxyServer{Config: cfg, router: r, HTTPPort: "3001"}
+	ps := &ProxyServer{
+		Config:         cfg,
+		router:         r,
+		certCache:      mtls.NewCache(customerCertCacheSize, nil),
+		HTTPPort:       "3001",
+		AdminPort:      "9090",
+		ManagementPort: "9091",
+		TLSPort:        "3443",
+	}
+	r.Use(requestIDMiddleware)
 	r.With(auth.Middleware(cfg)).HandleFunc("/metrics/job/{job}/instance/{instance}", ps.proxyHandler)
 	return ps
 }
 
-// Start launches the HTTP server and sets up signal handling for reloads.
+// requestIDMiddleware ensures every request carries an X-Request-ID,
+// generating one if the caller didn't supply it, so it can be propagated to
+// the upstream and tie together the proxy's and upstream's logs.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Request-ID") == "" {
+			r.Header.Set("X-Request-ID", newRequestID())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// tlsEnabled reports whether TLS_CERT and TLS_KEY are both set, turning on the HTTPS listener.
+func (p *ProxyServer) tlsEnabled() bool {
+	return p.TLSCertPath != "" && p.TLSKeyPath != ""
+}
+
+// loadTLSConfig builds the tls.Config for the HTTPS listener, requiring client
+// certificates signed by CLIENT_CA when one is configured.
+func (p *ProxyServer) loadTLSConfig() (*tls.Config, error) {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if p.ClientCAPath == "" {
+		return tlsCfg, nil
+	}
+
+	caPEM, err := os.ReadFile(p.ClientCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("failed to parse client CA %s", p.ClientCAPath)
+	}
+	tlsCfg.ClientCAs = pool
+	tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	return tlsCfg, nil
+}
+
+// adminRouter serves /metrics on its own listener, deliberately outside
+// auth.Middleware so scraping Prometheus doesn't need a customer API key.
+func adminRouter() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
+
+// httpHandler returns the Handler for the plaintext HTTP listener. Serving
+// p.router here would let X-API-Key and request bodies travel in cleartext,
+// defeating the point of enabling TLS, so once TLS is configured the HTTP
+// listener only answers health checks and redirects everything else to
+// HTTPS. It falls back to serving the real proxy only when TLS isn't
+// configured at all.
+func (p *ProxyServer) httpHandler() http.Handler {
+	if !p.tlsEnabled() {
+		return p.router
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		target := "https://" + host + ":" + p.TLSPort + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+	return mux
+}
+
+// Start launches the HTTP (and, when configured, HTTPS) server plus the
+// admin/metrics listener, and sets up signal handling for config reloads
+// and graceful shutdown.
 func (p *ProxyServer) Start() error {
 	// initial config load
 	if err := p.Config.Load(); err != nil {
 		return err
 	}
-	log.Printf("Configuration loaded: %d customers", p.Config.Count())
+	logger.Info("configuration loaded", "customers", p.Config.Count())
 
 	// hot reload on SIGHUP
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGHUP)
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
 	go func() {
-		for range sigs {
+		for range sighup {
 			if err := p.Config.Load(); err != nil {
-				log.Printf("ERROR: config reload failed: %v", err)
+				logger.Error("config reload failed", "error", err)
 			} else {
-				log.Printf("Configuration reloaded: %d customers", p.Config.Count())
+				p.certCache.InvalidateAll()
+				metrics.ConfigReloadsTotal.Inc()
+				logger.Info("configuration reloaded", "customers", p.Config.Count())
 			}
 		}
 	}()
 
-	port := os.Getenv("PORT")
-	if port != "" {
+	// optional hot reload on config file changes, in addition to SIGHUP
+	if os.Getenv("CONFIG_WATCH") == "true" {
+		watcher, err := p.Config.Watch(func(err error) {
+			if err != nil {
+				logger.Error("config watch reload failed", "error", err)
+				return
+			}
+			p.certCache.InvalidateAll()
+			metrics.ConfigReloadsTotal.Inc()
+			logger.Info("configuration reloaded from file watch", "customers", p.Config.Count())
+		})
+		if err != nil {
+			logger.Error("failed to watch config file", "error", err)
+		} else {
+			defer watcher.Close()
+		}
+	}
+
+	if port := os.Getenv("PORT"); port != "" {
 		p.HTTPPort = port
 	}
-	log.Printf("Starting mTLS proxy server on port %s", p.HTTPPort)
-	return http.ListenAndServe(":"+p.HTTPPort, p.router)
+	if adminPort := os.Getenv("ADMIN_PORT"); adminPort != "" {
+		p.AdminPort = adminPort
+	}
+	if mgmtPort := os.Getenv("MANAGEMENT_PORT"); mgmtPort != "" {
+		p.ManagementPort = mgmtPort
+	}
+	p.AdminToken = os.Getenv("ADMIN_TOKEN")
+	p.TLSCertPath = os.Getenv("TLS_CERT")
+	p.TLSKeyPath = os.Getenv("TLS_KEY")
+	p.ClientCAPath = os.Getenv("CLIENT_CA")
+	if tlsPort := os.Getenv("TLS_PORT"); tlsPort != "" {
+		p.TLSPort = tlsPort
+	}
+
+	httpSrv := &http.Server{Addr: ":" + p.HTTPPort, Handler: p.httpHandler()}
+	adminSrv := &http.Server{Addr: ":" + p.AdminPort, Handler: adminRouter()}
+	mgmtSrv := &http.Server{Addr: "127.0.0.1:" + p.ManagementPort, Handler: p.managementRouter(p.AdminToken)}
+
+	errCh := make(chan error, 4)
+	go func() {
+		logger.Info("starting mTLS proxy server", "port", p.HTTPPort)
+		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- fmt.Errorf("http listener: %w", err)
+		}
+	}()
+	go func() {
+		logger.Info("starting admin/metrics server", "port", p.AdminPort)
+		if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- fmt.Errorf("admin listener: %w", err)
+		}
+	}()
+	go func() {
+		logger.Info("starting management API server on loopback", "port", p.ManagementPort)
+		if err := mgmtSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- fmt.Errorf("management listener: %w", err)
+		}
+	}()
+
+	var httpsSrv *http.Server
+	if p.tlsEnabled() {
+		tlsCfg, err := p.loadTLSConfig()
+		if err != nil {
+			return err
+		}
+		httpsSrv = &http.Server{Addr: ":" + p.TLSPort, Handler: p.router, TLSConfig: tlsCfg}
+		go func() {
+			logger.Info("starting mTLS proxy HTTPS listener", "port", p.TLSPort)
+			if err := httpsSrv.ListenAndServeTLS(p.TLSCertPath, p.TLSKeyPath); err != nil && err != http.ErrServerClosed {
+				errCh <- fmt.Errorf("https listener: %w", err)
+			}
+		}()
+	}
+
+	sigterm := make(chan os.Signal, 1)
+	signal.Notify(sigterm, syscall.SIGTERM, syscall.SIGINT)
+
+	select {
+	case err := <-errCh:
+		return err
+	case sig := <-sigterm:
+		logger.Info("received signal, shutting down", "signal", sig.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := httpSrv.Shutdown(ctx); err != nil {
+		logger.Error("HTTP shutdown failed", "error", err)
+	}
+	if err := adminSrv.Shutdown(ctx); err != nil {
+		logger.Error("admin listener shutdown failed", "error", err)
+	}
+	if err := mgmtSrv.Shutdown(ctx); err != nil {
+		logger.Error("management listener shutdown failed", "error", err)
+	}
+	if httpsSrv != nil {
+		if err := httpsSrv.Shutdown(ctx); err != nil {
+			logger.Error("HTTPS shutdown failed", "error", err)
+		}
+	}
+	return nil
 }
 
-// proxyHandler forwards the request using mTLS credentials for the customer.
+// proxyHandler forwards the request to the customer's upstream over mTLS
+// using an httputil.ReverseProxy, with per-customer timeouts and retries.
 func (p *ProxyServer) proxyHandler(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Target-URL")
-	certPath := r.Header.Get("X-Cert-Path")
-	keyPath := r.Header.Get("X-Key-Path")
-
+	customerID := r.Header.Get("X-Customer-ID")
+	requestID := r.Header.Get("X-Request-ID")
 	job := chi.URLParam(r, "job")
 	instance := chi.URLParam(r, "instance")
-	targetURL := fmt.Sprintf("%s/metrics/job/%s/instance/%s", target, job, instance)
 
-	client, err := mtls.Client(certPath, keyPath)
-	if err != nil {
-		log.Printf("ERROR: failed to load client certificate: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	reqLogger := logger.With("customer_id", customerID, "job", job, "instance", instance, "request_id", requestID)
+
+	customer, ok := p.Config.Get(customerID)
+	if !ok {
+		http.Error(w, "Unauthorized: Invalid API Key", http.StatusUnauthorized)
 		return
 	}
 
-	proxyReq, err := http.NewRequest(r.Method, targetURL, r.Body)
+	client, err := p.certCache.Get(mtls.CustomerCert{
+		CustomerID: customerID,
+		CertSource: customer.CertSource,
+		CertPath:   customer.CertPath,
+		KeyPath:    customer.KeyPath,
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		reqLogger.Error("failed to load client certificate", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	proxyReq.Header.Set("Content-Type", r.Header.Get("Content-Type"))
 
-	resp, err := client.Do(proxyReq)
+	proxy, err := newReverseProxy(customer, client, customerID, job, instance, reqLogger)
 	if err != nil {
-		log.Printf("ERROR: proxy request failed: %v", err)
-		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		reqLogger.Error("failed to build reverse proxy", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
 
-	w.WriteHeader(resp.StatusCode)
-	io.Copy(w, resp.Body)
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	proxy.ServeHTTP(w, r)
 }
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mtls-proxy/internal/config"
+)
+
+func TestHTTPHandlerServesRouterWhenTLSDisabled(t *testing.T) {
+	p := New(config.NewManager("unused.json"))
+
+	if p.httpHandler() == nil {
+		t.Fatal("expected a non-nil handler")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics/job/x/instance/y", nil)
+	rec := httptest.NewRecorder()
+	p.httpHandler().ServeHTTP(rec, req)
+
+	// With no customer configured this should reach the proxy handler's auth
+	// check (401), not a TLS redirect (301) — proving the plaintext router
+	// is still in use when TLS isn't configured.
+	if rec.Code == http.StatusMovedPermanently {
+		t.Fatal("expected the plaintext router to handle the request, not a redirect, when TLS is disabled")
+	}
+}
+
+func TestHTTPHandlerRedirectsWhenTLSEnabled(t *testing.T) {
+	p := New(config.NewManager("unused.json"))
+	p.TLSCertPath = "cert.pem"
+	p.TLSKeyPath = "key.pem"
+	p.TLSPort = "3443"
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics/job/x/instance/y", nil)
+	req.Host = "proxy.example.com:3001"
+	rec := httptest.NewRecorder()
+	p.httpHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("expected a 301 redirect to HTTPS, got %d", rec.Code)
+	}
+	location := rec.Header().Get("Location")
+	want := "https://proxy.example.com:3443/metrics/job/x/instance/y"
+	if location != want {
+		t.Fatalf("expected redirect to %q, got %q", want, location)
+	}
+}
+
+func TestHTTPHandlerHealthzWhenTLSEnabled(t *testing.T) {
+	p := New(config.NewManager("unused.json"))
+	p.TLSCertPath = "cert.pem"
+	p.TLSKeyPath = "key.pem"
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	p.httpHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected /healthz to return 200 even with TLS enabled, got %d", rec.Code)
+	}
+}